@@ -0,0 +1,70 @@
+package base
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+)
+
+// HeaderValue is the value of a header.
+type HeaderValue []string
+
+// Header is a RTSP reader/writer header.
+type Header map[string]HeaderValue
+
+var errMissingNewLine = errors.New("expected '\\n'")
+
+func discardNewLine(rb *bufio.Reader) error {
+	byt, err := rb.ReadByte()
+	if err != nil {
+		return err
+	}
+	if byt != '\n' {
+		return errMissingNewLine
+	}
+	return nil
+}
+
+func readHeader(rb *bufio.Reader) (Header, error) {
+	h := make(Header)
+
+	for {
+		line, err := rb.ReadString('\r')
+		if err != nil {
+			return nil, err
+		}
+		line = line[:len(line)-1]
+
+		if err := discardNewLine(rb); err != nil {
+			return nil, err
+		}
+
+		if line == "" {
+			break
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimLeft(line[i+1:], " ")
+
+		h[key] = append(h[key], value)
+	}
+
+	return h, nil
+}
+
+func (h Header) write(wb *bufio.Writer) error {
+	for key, values := range h {
+		for _, value := range values {
+			if _, err := wb.WriteString(key + ": " + value + "\r\n"); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := wb.WriteString("\r\n")
+	return err
+}
@@ -0,0 +1,62 @@
+package base
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const interleavedFrameMagicByte = 0x24 // '$'
+
+// InterleavedFrame is a RTP/RTCP frame interleaved on a RTSP/TCP connection,
+// as defined in RFC2326, section 10.12. Channel is the interleaved channel
+// number the frame was read from, or should be written to; it carries no
+// inherent meaning of its own and is only meaningful in combination with the
+// channel pair negotiated for a track in the SETUP request/response (see
+// headers.Transport.InterleavedIds).
+type InterleavedFrame struct {
+	// Channel is the interleaved channel the frame belongs to.
+	Channel int
+
+	// Payload is the frame payload.
+	Payload []byte
+}
+
+// Read reads an InterleavedFrame. Payload must be already allocated with a
+// capacity large enough to hold the incoming frame.
+func (f *InterleavedFrame) Read(rb *bufio.Reader) error {
+	var header [4]byte
+	if _, err := io.ReadFull(rb, header[:]); err != nil {
+		return err
+	}
+
+	if header[0] != interleavedFrameMagicByte {
+		return fmt.Errorf("invalid magic byte")
+	}
+
+	f.Channel = int(header[1])
+
+	plen := int(binary.BigEndian.Uint16(header[2:4]))
+	if plen > cap(f.Payload) {
+		return fmt.Errorf("frame size (%d) greater than buffer size (%d)", plen, cap(f.Payload))
+	}
+	f.Payload = f.Payload[:plen]
+
+	_, err := io.ReadFull(rb, f.Payload)
+	return err
+}
+
+// Write writes an InterleavedFrame.
+func (f InterleavedFrame) Write(wb *bufio.Writer) error {
+	header := [4]byte{interleavedFrameMagicByte, byte(f.Channel), 0, 0}
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(f.Payload)))
+
+	if _, err := wb.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := wb.Write(f.Payload); err != nil {
+		return err
+	}
+	return wb.Flush()
+}
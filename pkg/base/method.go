@@ -0,0 +1,18 @@
+package base
+
+// Method is a RTSP method.
+type Method string
+
+// standard methods.
+const (
+	Announce     Method = "ANNOUNCE"
+	Describe     Method = "DESCRIBE"
+	GetParameter Method = "GET_PARAMETER"
+	Options      Method = "OPTIONS"
+	Pause        Method = "PAUSE"
+	Play         Method = "PLAY"
+	Record       Method = "RECORD"
+	Setup        Method = "SETUP"
+	SetParameter Method = "SET_PARAMETER"
+	Teardown     Method = "TEARDOWN"
+)
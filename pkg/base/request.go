@@ -0,0 +1,103 @@
+package base
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	rtspProtocol10 = "RTSP/1.0"
+)
+
+// Request is a RTSP request.
+type Request struct {
+	// Method is the method of the request.
+	Method Method
+
+	// URL is the URL of the request.
+	URL *URL
+
+	// Header is the header of the request.
+	Header Header
+
+	// Body is the optional body of the request.
+	Body []byte
+}
+
+// Read reads a request.
+func (req *Request) Read(rb *bufio.Reader) error {
+	line, err := rb.ReadString('\r')
+	if err != nil {
+		return err
+	}
+	line = line[:len(line)-1]
+
+	if err := discardNewLine(rb); err != nil {
+		return err
+	}
+
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid request line: %v", line)
+	}
+
+	req.Method = Method(parts[0])
+
+	u, err := ParseURL(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid request URL: %v", parts[1])
+	}
+	req.URL = u
+
+	req.Header, err = readHeader(rb)
+	if err != nil {
+		return err
+	}
+
+	if cl, ok := req.Header["Content-Length"]; ok && len(cl) == 1 {
+		length, err := strconv.Atoi(cl[0])
+		if err != nil {
+			return fmt.Errorf("invalid Content-Length: %v", cl[0])
+		}
+
+		req.Body = make([]byte, length)
+		if _, err := io.ReadFull(rb, req.Body); err != nil {
+			return err
+		}
+	} else {
+		req.Body = nil
+	}
+
+	return nil
+}
+
+// Write writes a request.
+func (req Request) Write(wb *bufio.Writer) error {
+	if _, err := wb.WriteString(string(req.Method) + " " + req.URL.String() + " " + rtspProtocol10 + "\r\n"); err != nil {
+		return err
+	}
+
+	header := make(Header)
+	for k, v := range req.Header {
+		header[k] = v
+	}
+
+	if len(req.Body) != 0 {
+		header["Content-Length"] = HeaderValue{strconv.Itoa(len(req.Body))}
+	}
+
+	if err := header.write(wb); err != nil {
+		return err
+	}
+
+	if len(req.Body) != 0 {
+		if _, err := wb.Write(req.Body); err != nil {
+			return err
+		}
+	}
+
+	return wb.Flush()
+}
@@ -0,0 +1,127 @@
+package base
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Response is a RTSP response.
+type Response struct {
+	// StatusCode is the status code of the response.
+	StatusCode StatusCode
+
+	// StatusMessage is the status message of the response. If empty, it is
+	// automatically filled with a message associated with StatusCode.
+	StatusMessage string
+
+	// Header is the header of the response.
+	Header Header
+
+	// Body is the optional body of the response.
+	Body []byte
+}
+
+// Read reads a response.
+func (res *Response) Read(rb *bufio.Reader) error {
+	line, err := rb.ReadString('\r')
+	if err != nil {
+		return err
+	}
+	line = line[:len(line)-1]
+
+	if err := discardNewLine(rb); err != nil {
+		return err
+	}
+
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid status line: %v", line)
+	}
+
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid status code: %v", parts[1])
+	}
+	res.StatusCode = StatusCode(code)
+	res.StatusMessage = parts[2]
+
+	res.Header, err = readHeader(rb)
+	if err != nil {
+		return err
+	}
+
+	if cl, ok := res.Header["Content-Length"]; ok && len(cl) == 1 {
+		length, err := strconv.Atoi(cl[0])
+		if err != nil {
+			return fmt.Errorf("invalid Content-Length: %v", cl[0])
+		}
+
+		res.Body = make([]byte, length)
+		if _, err := io.ReadFull(rb, res.Body); err != nil {
+			return err
+		}
+	} else {
+		res.Body = nil
+	}
+
+	return nil
+}
+
+// ReadIgnoreFrames reads a response while discarding any interleaved frame
+// that precedes it on the same connection. buf is used to store the
+// discarded frame payloads.
+func (res *Response) ReadIgnoreFrames(rb *bufio.Reader, buf []byte) error {
+	for {
+		byt, err := rb.Peek(1)
+		if err != nil {
+			return err
+		}
+
+		if byt[0] == interleavedFrameMagicByte {
+			var fr InterleavedFrame
+			fr.Payload = buf
+			if err := fr.Read(rb); err != nil {
+				return err
+			}
+			continue
+		}
+
+		return res.Read(rb)
+	}
+}
+
+// Write writes a response.
+func (res Response) Write(wb *bufio.Writer) error {
+	if res.StatusMessage == "" {
+		res.StatusMessage = StatusMessage(res.StatusCode)
+	}
+
+	if _, err := wb.WriteString(rtspProtocol10 + " " +
+		strconv.Itoa(int(res.StatusCode)) + " " + res.StatusMessage + "\r\n"); err != nil {
+		return err
+	}
+
+	header := make(Header)
+	for k, v := range res.Header {
+		header[k] = v
+	}
+
+	if len(res.Body) != 0 {
+		header["Content-Length"] = HeaderValue{strconv.Itoa(len(res.Body))}
+	}
+
+	if err := header.write(wb); err != nil {
+		return err
+	}
+
+	if len(res.Body) != 0 {
+		if _, err := wb.Write(res.Body); err != nil {
+			return err
+		}
+	}
+
+	return wb.Flush()
+}
@@ -0,0 +1,45 @@
+package base
+
+// StatusCode is a RTSP response status code.
+type StatusCode int
+
+// standard status codes.
+const (
+	StatusOK                   StatusCode = 200
+	StatusMovedPermanently     StatusCode = 301
+	StatusFound                StatusCode = 302
+	StatusBadRequest           StatusCode = 400
+	StatusUnauthorized         StatusCode = 401
+	StatusNotFound             StatusCode = 404
+	StatusMethodNotAllowed     StatusCode = 405
+	StatusSessionNotFound      StatusCode = 454
+	StatusUnsupportedTransport StatusCode = 461
+	StatusInternalServerError  StatusCode = 500
+)
+
+// StatusMessage returns the message associated with a status code.
+func StatusMessage(code StatusCode) string {
+	switch code {
+	case StatusOK:
+		return "OK"
+	case StatusMovedPermanently:
+		return "Moved Permanently"
+	case StatusFound:
+		return "Found"
+	case StatusBadRequest:
+		return "Bad Request"
+	case StatusUnauthorized:
+		return "Unauthorized"
+	case StatusNotFound:
+		return "Not Found"
+	case StatusMethodNotAllowed:
+		return "Method Not Allowed"
+	case StatusSessionNotFound:
+		return "Session Not Found"
+	case StatusUnsupportedTransport:
+		return "Unsupported Transport"
+	case StatusInternalServerError:
+		return "Internal Server Error"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,21 @@
+package base
+
+// StreamDelivery is the delivery method of a stream.
+type StreamDelivery int
+
+// stream deliveries.
+const (
+	StreamDeliveryUnicast StreamDelivery = iota
+	StreamDeliveryMulticast
+)
+
+// String implements fmt.Stringer.
+func (sd StreamDelivery) String() string {
+	switch sd {
+	case StreamDeliveryUnicast:
+		return "unicast"
+	case StreamDeliveryMulticast:
+		return "multicast"
+	}
+	return "unknown"
+}
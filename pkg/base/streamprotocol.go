@@ -0,0 +1,21 @@
+package base
+
+// StreamProtocol is the protocol used to stream RTP/RTCP frames.
+type StreamProtocol int
+
+// stream protocols.
+const (
+	StreamProtocolUDP StreamProtocol = iota
+	StreamProtocolTCP
+)
+
+// String implements fmt.Stringer.
+func (sp StreamProtocol) String() string {
+	switch sp {
+	case StreamProtocolUDP:
+		return "UDP"
+	case StreamProtocolTCP:
+		return "TCP"
+	}
+	return "unknown"
+}
@@ -0,0 +1,21 @@
+package base
+
+// StreamType is the stream type.
+type StreamType int
+
+// stream types.
+const (
+	StreamTypeRTP StreamType = iota
+	StreamTypeRTCP
+)
+
+// String implements fmt.Stringer.
+func (st StreamType) String() string {
+	switch st {
+	case StreamTypeRTP:
+		return "RTP"
+	case StreamTypeRTCP:
+		return "RTCP"
+	}
+	return "unknown"
+}
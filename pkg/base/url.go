@@ -0,0 +1,27 @@
+package base
+
+import (
+	"net/url"
+)
+
+// URL is a RTSP URL.
+type URL = url.URL
+
+// ParseURL parses a RTSP URL.
+func ParseURL(s string) (*URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// MustParseURL is like ParseURL but panics if the URL cannot be parsed.
+// It is intended for use in tests.
+func MustParseURL(s string) *URL {
+	u, err := ParseURL(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
@@ -0,0 +1,174 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// Transport is a Transport header.
+type Transport struct {
+	// Protocol is the stream protocol (UDP or TCP).
+	Protocol base.StreamProtocol
+
+	// Delivery is the stream delivery method (unicast or multicast).
+	Delivery *base.StreamDelivery
+
+	// Mode is the transport mode (play or record).
+	Mode *TransportMode
+
+	// InterleavedIds is the channel pair used by TCP streams.
+	InterleavedIds *[2]int
+
+	// ClientPorts is the port pair announced by the client for UDP streams.
+	ClientPorts *[2]int
+
+	// ServerPorts is the port pair announced by the server for UDP streams.
+	ServerPorts *[2]int
+
+	// Destination is the multicast destination address.
+	Destination *string
+
+	// TTL is the multicast time-to-live.
+	TTL *uint
+}
+
+func readPortPair(s string) (*[2]int, error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid port pair: %v", s)
+	}
+
+	p1, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	p2, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &[2]int{p1, p2}, nil
+}
+
+// ReadTransport parses a Transport header.
+func ReadTransport(v base.HeaderValue) (*Transport, error) {
+	if len(v) == 0 {
+		return nil, fmt.Errorf("value not provided")
+	}
+
+	th := &Transport{}
+
+	for _, key := range strings.Split(v[0], ";") {
+		key = strings.TrimSpace(key)
+
+		switch {
+		case key == "RTP/AVP" || key == "RTP/AVP/UDP":
+			th.Protocol = base.StreamProtocolUDP
+
+		case key == "RTP/AVP/TCP":
+			th.Protocol = base.StreamProtocolTCP
+
+		case key == "unicast":
+			v := base.StreamDeliveryUnicast
+			th.Delivery = &v
+
+		case key == "multicast":
+			v := base.StreamDeliveryMulticast
+			th.Delivery = &v
+
+		case strings.HasPrefix(key, "mode="):
+			switch strings.ToLower(strings.Trim(key[len("mode="):], "\"")) {
+			case "play":
+				v := TransportModePlay
+				th.Mode = &v
+			case "record":
+				v := TransportModeRecord
+				th.Mode = &v
+			}
+
+		case strings.HasPrefix(key, "interleaved="):
+			ports, err := readPortPair(key[len("interleaved="):])
+			if err != nil {
+				return nil, err
+			}
+			th.InterleavedIds = ports
+
+		case strings.HasPrefix(key, "client_port="):
+			ports, err := readPortPair(key[len("client_port="):])
+			if err != nil {
+				return nil, err
+			}
+			th.ClientPorts = ports
+
+		case strings.HasPrefix(key, "server_port="):
+			ports, err := readPortPair(key[len("server_port="):])
+			if err != nil {
+				return nil, err
+			}
+			th.ServerPorts = ports
+
+		case strings.HasPrefix(key, "destination="):
+			v := key[len("destination="):]
+			th.Destination = &v
+
+		case strings.HasPrefix(key, "ttl="):
+			n, err := strconv.ParseUint(key[len("ttl="):], 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			v := uint(n)
+			th.TTL = &v
+		}
+	}
+
+	return th, nil
+}
+
+// Write encodes a Transport header.
+func (th Transport) Write() base.HeaderValue {
+	var parts []string
+
+	if th.Protocol == base.StreamProtocolTCP {
+		parts = append(parts, "RTP/AVP/TCP")
+	} else {
+		parts = append(parts, "RTP/AVP")
+	}
+
+	if th.Delivery != nil {
+		parts = append(parts, th.Delivery.String())
+	}
+
+	if th.Destination != nil {
+		parts = append(parts, "destination="+*th.Destination)
+	}
+
+	if th.TTL != nil {
+		parts = append(parts, "ttl="+strconv.FormatUint(uint64(*th.TTL), 10))
+	}
+
+	if th.InterleavedIds != nil {
+		parts = append(parts, "interleaved="+strconv.Itoa(th.InterleavedIds[0])+"-"+strconv.Itoa(th.InterleavedIds[1]))
+	}
+
+	if th.ClientPorts != nil {
+		parts = append(parts, "client_port="+strconv.Itoa(th.ClientPorts[0])+"-"+strconv.Itoa(th.ClientPorts[1]))
+	}
+
+	if th.ServerPorts != nil {
+		parts = append(parts, "server_port="+strconv.Itoa(th.ServerPorts[0])+"-"+strconv.Itoa(th.ServerPorts[1]))
+	}
+
+	if th.Mode != nil {
+		if *th.Mode == TransportModeRecord {
+			parts = append(parts, "mode=record")
+		} else {
+			parts = append(parts, "mode=play")
+		}
+	}
+
+	return base.HeaderValue{strings.Join(parts, ";")}
+}
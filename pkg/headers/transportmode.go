@@ -0,0 +1,10 @@
+package headers
+
+// TransportMode is the mode of a Transport header.
+type TransportMode int
+
+// transport modes.
+const (
+	TransportModePlay TransportMode = iota
+	TransportModeRecord
+)
@@ -0,0 +1,110 @@
+// Package rtcpreceiver contains a utility that generates RTCP receiver
+// reports from a stream of incoming RTP packets.
+package rtcpreceiver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// RTCPReceiver accumulates statistics about an incoming RTP stream (as seen
+// by a RECORD session) and produces RTCP receiver reports, as described in
+// RFC 3550, section 6.4.2.
+type RTCPReceiver struct {
+	receiverSSRC uint32
+
+	mutex          sync.Mutex
+	firstPacket    bool
+	senderSSRC     uint32
+	totalLost      uint32
+	lastSequence   uint16
+	lastFrameTime  time.Time
+	lastSenderTime time.Time
+}
+
+// New allocates a RTCPReceiver. receiverSSRC identifies the server in the
+// reports it generates.
+func New(receiverSSRC uint32) *RTCPReceiver {
+	return &RTCPReceiver{
+		receiverSSRC: receiverSSRC,
+		firstPacket:  true,
+	}
+}
+
+// ProcessPacket updates the statistics with an incoming RTP packet.
+func (rr *RTCPReceiver) ProcessPacket(payload []byte, now time.Time) error {
+	var pkt rtp.Packet
+	if err := pkt.Unmarshal(payload); err != nil {
+		return err
+	}
+
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	rr.senderSSRC = pkt.SSRC
+	rr.lastFrameTime = now
+
+	if rr.firstPacket {
+		rr.firstPacket = false
+		rr.lastSequence = pkt.SequenceNumber - 1
+	}
+
+	if expected := rr.lastSequence + 1; pkt.SequenceNumber != expected {
+		// reinterpret the difference as signed before widening it: with
+		// plain uint16 arithmetic, a reordered or duplicate packet (whose
+		// sequence number is behind expected) wraps around to a huge bogus
+		// gap instead of being recognized as not-a-loss.
+		if diff := int16(pkt.SequenceNumber - expected); diff > 0 {
+			rr.totalLost += uint32(diff)
+		}
+	}
+	rr.lastSequence = pkt.SequenceNumber
+
+	return nil
+}
+
+// ProcessSenderReport updates the statistics with a RTCP sender report
+// received from the publisher, used to compute the delay reported in the
+// next receiver report.
+func (rr *RTCPReceiver) ProcessSenderReport(now time.Time) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	rr.lastSenderTime = now
+}
+
+// Report generates a RTCP receiver report with the statistics collected so
+// far.
+func (rr *RTCPReceiver) Report(now time.Time) *rtcp.ReceiverReport {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	var lastSenderReport uint32
+	var delay uint32
+	if !rr.lastSenderTime.IsZero() {
+		lastSenderReport = uint32(rr.lastSenderTime.Unix())
+		delay = uint32(now.Sub(rr.lastSenderTime).Seconds() * 65536)
+	}
+
+	return &rtcp.ReceiverReport{
+		SSRC: rr.receiverSSRC,
+		Reports: []rtcp.ReceptionReport{
+			{
+				SSRC:               rr.senderSSRC,
+				TotalLost:          rr.totalLost,
+				LastSequenceNumber: uint32(rr.lastSequence),
+				LastSenderReport:   lastSenderReport,
+				Delay:              delay,
+			},
+		},
+	}
+}
+
+// LastFrameTime returns the time the last RTP packet was processed.
+func (rr *RTCPReceiver) LastFrameTime() time.Time {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	return rr.lastFrameTime
+}
@@ -0,0 +1,66 @@
+package rtcpreceiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, seq uint16) []byte {
+	buf, err := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			SequenceNumber: seq,
+			SSRC:           123,
+		},
+		Payload: []byte{0x01},
+	}).Marshal()
+	require.NoError(t, err)
+	return buf
+}
+
+func TestRTCPReceiverReport(t *testing.T) {
+	rr := New(456)
+
+	err := rr.ProcessPacket(encode(t, 100), time.Now())
+	require.NoError(t, err)
+
+	report := rr.Report(time.Now())
+	require.Equal(t, uint32(456), report.SSRC)
+	require.Equal(t, uint32(123), report.Reports[0].SSRC)
+	require.Equal(t, uint32(0), report.Reports[0].TotalLost)
+	require.Equal(t, uint32(100), report.Reports[0].LastSequenceNumber)
+}
+
+func TestRTCPReceiverReportLoss(t *testing.T) {
+	rr := New(456)
+
+	err := rr.ProcessPacket(encode(t, 100), time.Now())
+	require.NoError(t, err)
+
+	err = rr.ProcessPacket(encode(t, 105), time.Now())
+	require.NoError(t, err)
+
+	report := rr.Report(time.Now())
+	require.Equal(t, uint32(4), report.Reports[0].TotalLost)
+}
+
+func TestRTCPReceiverReportReorderedDoesNotUnderflow(t *testing.T) {
+	rr := New(456)
+
+	err := rr.ProcessPacket(encode(t, 100), time.Now())
+	require.NoError(t, err)
+
+	err = rr.ProcessPacket(encode(t, 105), time.Now())
+	require.NoError(t, err)
+
+	// a packet arriving out of order, behind the last-seen sequence number,
+	// must not be accounted as a huge loss due to unsigned wraparound.
+	err = rr.ProcessPacket(encode(t, 99), time.Now())
+	require.NoError(t, err)
+
+	report := rr.Report(time.Now())
+	require.Equal(t, uint32(4), report.Reports[0].TotalLost)
+}
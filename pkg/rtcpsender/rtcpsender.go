@@ -0,0 +1,85 @@
+// Package rtcpsender contains a utility that generates RTCP sender reports
+// from a stream of outgoing RTP packets.
+package rtcpsender
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// RTCPSender accumulates statistics about an outgoing RTP stream (as seen
+// by a PLAY session) and produces RTCP sender reports, as described in
+// RFC 3550, section 6.4.1.
+type RTCPSender struct {
+	mutex         sync.Mutex
+	ssrc          uint32
+	ssrcSet       bool
+	packetCount   uint32
+	octetCount    uint32
+	lastRTPTime   uint32
+	lastFrameTime time.Time
+}
+
+// New allocates a RTCPSender.
+func New() *RTCPSender {
+	return &RTCPSender{}
+}
+
+// ProcessPacket updates the statistics with an outgoing RTP packet.
+func (rs *RTCPSender) ProcessPacket(payload []byte, now time.Time) error {
+	var pkt rtp.Packet
+	if err := pkt.Unmarshal(payload); err != nil {
+		return err
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	rs.ssrc = pkt.SSRC
+	rs.ssrcSet = true
+	rs.packetCount++
+	rs.octetCount += uint32(len(pkt.Payload))
+	rs.lastRTPTime = pkt.Timestamp
+	rs.lastFrameTime = now
+
+	return nil
+}
+
+// Report generates a RTCP sender report with the statistics collected so
+// far, or nil if no RTP packet has been processed yet.
+func (rs *RTCPSender) Report(now time.Time) *rtcp.SenderReport {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	if !rs.ssrcSet {
+		return nil
+	}
+
+	return &rtcp.SenderReport{
+		SSRC:        rs.ssrc,
+		NTPTime:     ntpTime(now),
+		RTPTime:     rs.lastRTPTime,
+		PacketCount: rs.packetCount,
+		OctetCount:  rs.octetCount,
+	}
+}
+
+// LastFrameTime returns the time the last RTP packet was processed.
+func (rs *RTCPSender) LastFrameTime() time.Time {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	return rs.lastFrameTime
+}
+
+// ntpTime converts a time.Time into a NTP64 timestamp, as used in RTCP
+// sender reports.
+func ntpTime(t time.Time) uint64 {
+	const ntpEpochOffset = 2208988800 // seconds between 1900 and 1970
+
+	sec := uint64(t.Unix()) + ntpEpochOffset
+	frac := uint64(t.Nanosecond()) * (1 << 32) / 1e9
+	return sec<<32 | frac
+}
@@ -0,0 +1,78 @@
+package gortsplib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	psdp "github.com/pion/sdp/v2"
+)
+
+// ReadTracks parses a SDP session description, as received in an ANNOUNCE or
+// DESCRIBE request/response, into a list of tracks. The track ID is taken
+// from each media's "control" attribute (control:trackID=N); if absent, the
+// media's position in the session description is used instead.
+func ReadTracks(byts []byte) (Tracks, error) {
+	var sd psdp.SessionDescription
+	if err := sd.Unmarshal(byts); err != nil {
+		return nil, err
+	}
+
+	tracks := make(Tracks, 0, len(sd.MediaDescriptions))
+
+	for i, md := range sd.MediaDescriptions {
+		trackID := i
+
+		if control, ok := md.Attribute("control"); ok {
+			if n, err := strconv.Atoi(strings.TrimPrefix(control, "trackID=")); err == nil {
+				trackID = n
+			}
+		}
+
+		tracks = append(tracks, &Track{
+			ID:    trackID,
+			Media: md,
+		})
+	}
+
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no media found in SDP")
+	}
+
+	return tracks, nil
+}
+
+// WriteTracks encodes a list of tracks into a SDP session description, as
+// returned in a DESCRIBE response. A media without a "control" attribute is
+// given "trackID=N", so that the track can be recovered with ReadTracks; an
+// existing "control" attribute is left as it is.
+func WriteTracks(tracks Tracks) []byte {
+	sd := &psdp.SessionDescription{
+		Origin: psdp.Origin{
+			Username:       "-",
+			NetworkType:    "IN",
+			AddressType:    "IP4",
+			UnicastAddress: "0.0.0.0",
+		},
+		SessionName: psdp.SessionName("Stream"),
+		TimeDescriptions: []psdp.TimeDescription{
+			{Timing: psdp.Timing{StartTime: 0, StopTime: 0}},
+		},
+	}
+
+	for _, track := range tracks {
+		md := track.Media
+
+		if _, ok := md.Attribute("control"); !ok {
+			md.Attributes = append(md.Attributes, psdp.Attribute{
+				Key:   "control",
+				Value: "trackID=" + strconv.Itoa(track.ID),
+			})
+		}
+
+		sd.MediaDescriptions = append(sd.MediaDescriptions, md)
+	}
+
+	byts, _ := sd.Marshal()
+	return byts
+}
@@ -0,0 +1,494 @@
+package gortsplib
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// ServerConf allows to configure a Server.
+type ServerConf struct {
+	// UDPRTPAddress, if filled, allows to receive/send UDP RTP packets,
+	// and is used as a base address for every UDP RTP listener.
+	UDPRTPAddress string
+
+	// UDPRTCPAddress, if filled, allows to receive/send UDP RTCP packets,
+	// and is used as a base address for every UDP RTCP listener.
+	UDPRTCPAddress string
+
+	// MulticastIPRange, if filled, enables multicast delivery to readers
+	// and is the CIDR range (e.g. "224.1.0.0/16") multicast groups are
+	// allocated from, one group per published path/track.
+	MulticastIPRange string
+
+	// MulticastTTL is the TTL announced to clients for multicast streams.
+	// It defaults to 16.
+	MulticastTTL uint
+
+	// ReceiverReportInterval is the interval between RTCP receiver reports
+	// automatically sent to publishers during RECORD. It defaults to 10sec,
+	// as recommended by RFC3550, section 6.4.
+	ReceiverReportInterval time.Duration
+
+	// SenderReportInterval is the interval between RTCP sender reports
+	// automatically sent to readers during PLAY. It defaults to 10sec, as
+	// recommended by RFC3550, section 6.4.
+	SenderReportInterval time.Duration
+
+	// StreamDeadAfter is the amount of time after which a session is closed
+	// if no RTP/RTCP packet has been received from its publisher. It
+	// defaults to 10sec.
+	StreamDeadAfter time.Duration
+
+	// TLSConfig is the TLS configuration used by ServeTLS to accept
+	// rtsps:// connections. It is required by ServeTLS.
+	TLSConfig *tls.Config
+
+	// ReadTimeout is the maximum amount of time allowed to read a request
+	// or a frame from a connection. It is reset before every read. Zero
+	// means that no timeout is applied.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the maximum amount of time allowed to write a
+	// response or a frame to a connection. It is reset before every write.
+	// Zero means that no timeout is applied.
+	WriteTimeout time.Duration
+}
+
+// udpRoute identifies the track a UDP packet, received from a given source
+// address, belongs to.
+type udpRoute struct {
+	sc      *ServerConn
+	trackID int
+}
+
+// serverUDPListener is a UDP socket shared by every ServerConn, used to
+// receive RTP or RTCP packets and dispatch them to the relevant track by
+// matching the packet's source address against the addresses registered
+// during SETUP.
+type serverUDPListener struct {
+	pc   *net.UDPConn
+	port int
+
+	mutex  sync.RWMutex
+	routes map[string]udpRoute
+}
+
+func newServerUDPListener(address string) (*serverUDPListener, error) {
+	if address == "" {
+		return nil, nil
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	_, portStr, err := net.SplitHostPort(pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	u := &serverUDPListener{
+		pc:     pc,
+		port:   port,
+		routes: make(map[string]udpRoute),
+	}
+
+	return u, nil
+}
+
+func (u *serverUDPListener) close() error {
+	return u.pc.Close()
+}
+
+func (u *serverUDPListener) addRoute(addr string, sc *ServerConn, trackID int) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.routes[addr] = udpRoute{sc: sc, trackID: trackID}
+}
+
+func (u *serverUDPListener) removeRoutesForConn(sc *ServerConn) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	for addr, r := range u.routes {
+		if r.sc == sc {
+			delete(u.routes, addr)
+		}
+	}
+}
+
+func (u *serverUDPListener) run(typ StreamType) {
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := u.pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		u.mutex.RLock()
+		route, ok := u.routes[addr.String()]
+		u.mutex.RUnlock()
+		if !ok {
+			continue
+		}
+
+		payload := make([]byte, n)
+		copy(payload, buf[:n])
+		route.sc.onIncomingFrame(route.trackID, typ, payload)
+	}
+}
+
+// serverMulticastListener is a pair of multicast UDP sockets (RTP and RTCP)
+// allocated for a single published path/track, shared by every reader that
+// sets up that path/track in multicast mode, so the publisher's packets are
+// only pushed once regardless of the number of readers.
+type serverMulticastListener struct {
+	ip       net.IP
+	rtpConn  *net.UDPConn
+	rtcpConn *net.UDPConn
+	rtpPort  int
+	rtcpPort int
+	refCount int
+}
+
+func newServerMulticastListener(s *Server) (*serverMulticastListener, error) {
+	ip, err := s.nextMulticastIP()
+	if err != nil {
+		return nil, err
+	}
+
+	rtpConn, err := net.ListenMulticastUDP("udp", nil, &net.UDPAddr{IP: ip, Port: 0})
+	if err != nil {
+		return nil, err
+	}
+
+	rtcpConn, err := net.ListenMulticastUDP("udp", nil, &net.UDPAddr{IP: ip, Port: 0})
+	if err != nil {
+		rtpConn.Close()
+		return nil, err
+	}
+
+	rtpPort, err := udpConnPort(rtpConn)
+	if err != nil {
+		rtpConn.Close()
+		rtcpConn.Close()
+		return nil, err
+	}
+
+	rtcpPort, err := udpConnPort(rtcpConn)
+	if err != nil {
+		rtpConn.Close()
+		rtcpConn.Close()
+		return nil, err
+	}
+
+	ttl := int(s.conf.MulticastTTL)
+	if err := ipv4.NewPacketConn(rtpConn).SetMulticastTTL(ttl); err != nil {
+		rtpConn.Close()
+		rtcpConn.Close()
+		return nil, err
+	}
+	if err := ipv4.NewPacketConn(rtcpConn).SetMulticastTTL(ttl); err != nil {
+		rtpConn.Close()
+		rtcpConn.Close()
+		return nil, err
+	}
+
+	return &serverMulticastListener{
+		ip:       ip,
+		rtpConn:  rtpConn,
+		rtcpConn: rtcpConn,
+		rtpPort:  rtpPort,
+		rtcpPort: rtcpPort,
+		refCount: 1,
+	}, nil
+}
+
+func (m *serverMulticastListener) close() {
+	m.rtpConn.Close()
+	m.rtcpConn.Close()
+}
+
+func (m *serverMulticastListener) write(typ StreamType, payload []byte) error {
+	conn := m.rtpConn
+	port := m.rtpPort
+	if typ == StreamTypeRTCP {
+		conn = m.rtcpConn
+		port = m.rtcpPort
+	}
+
+	_, err := conn.WriteTo(payload, &net.UDPAddr{IP: m.ip, Port: port})
+	return err
+}
+
+func udpConnPort(pc *net.UDPConn) (int, error) {
+	_, portStr, err := net.SplitHostPort(pc.LocalAddr().String())
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(portStr)
+}
+
+// Server is a RTSP server.
+type Server struct {
+	conf     ServerConf
+	listener net.Listener
+
+	udpRTPListener  *serverUDPListener
+	udpRTCPListener *serverUDPListener
+
+	multicastIPRange *net.IPNet
+	multicastMutex   sync.Mutex
+	multicastNextIP  net.IP
+	multicastGroups  map[string]*serverMulticastListener
+}
+
+// nextMulticastIP returns the next unused address in the configured
+// multicast range.
+func (s *Server) nextMulticastIP() (net.IP, error) {
+	s.multicastMutex.Lock()
+	defer s.multicastMutex.Unlock()
+
+	ip := s.multicastNextIP
+	if !s.multicastIPRange.Contains(ip) {
+		return nil, fmt.Errorf("multicast IP range exhausted")
+	}
+
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	s.multicastNextIP = next
+
+	return ip, nil
+}
+
+// multicastGroupKey returns the key a path/track's multicast listener is
+// registered under in Server.multicastGroups.
+func multicastGroupKey(path string, trackID int) string {
+	return path + "/" + strconv.Itoa(trackID)
+}
+
+// WriteFrameMulticast writes a RTP/RTCP frame to the multicast group shared
+// by every reader that has setup path/trackID in multicast mode. Unlike
+// ServerConn.WriteFrame, which targets a single connection, this reaches
+// every multicast reader of the track with a single UDP send; it is meant
+// to be called once per frame, typically from the publisher's OnFrame
+// handler. It is a no-op if path/trackID has no multicast readers.
+func (s *Server) WriteFrameMulticast(path string, trackID int, typ StreamType, payload []byte) error {
+	key := multicastGroupKey(path, trackID)
+
+	s.multicastMutex.Lock()
+	m, ok := s.multicastGroups[key]
+	s.multicastMutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return m.write(typ, payload)
+}
+
+// multicastListenerForTrack returns the multicast listener associated with
+// a published path/track, allocating one on first use. Readers that setup
+// the same path/track afterwards share the same listener.
+func (s *Server) multicastListenerForTrack(path string, trackID int) (*serverMulticastListener, string, error) {
+	key := multicastGroupKey(path, trackID)
+
+	s.multicastMutex.Lock()
+	if m, ok := s.multicastGroups[key]; ok {
+		m.refCount++
+		s.multicastMutex.Unlock()
+		return m, key, nil
+	}
+	s.multicastMutex.Unlock()
+
+	m, err := newServerMulticastListener(s)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.multicastMutex.Lock()
+	if existing, ok := s.multicastGroups[key]; ok {
+		existing.refCount++
+		s.multicastMutex.Unlock()
+		m.close()
+		return existing, key, nil
+	}
+	s.multicastGroups[key] = m
+	s.multicastMutex.Unlock()
+
+	return m, key, nil
+}
+
+// releaseMulticastListener decrements the reference count of the multicast
+// listener associated with key, closing it once no reader is left.
+func (s *Server) releaseMulticastListener(key string) {
+	s.multicastMutex.Lock()
+	defer s.multicastMutex.Unlock()
+
+	m, ok := s.multicastGroups[key]
+	if !ok {
+		return
+	}
+
+	m.refCount--
+	if m.refCount <= 0 {
+		delete(s.multicastGroups, key)
+		m.close()
+	}
+}
+
+// Serve starts listening on the given address with the default configuration.
+func Serve(address string) (*Server, error) {
+	return ServerConf{}.Serve(address)
+}
+
+// Serve starts listening on the given address.
+func (conf ServerConf) Serve(address string) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return conf.serve(listener)
+}
+
+// ServeTLS starts listening on the given address with the default
+// configuration, and encrypts every connection with TLS.
+func ServeTLS(address string, tlsConfig *tls.Config) (*Server, error) {
+	return ServerConf{TLSConfig: tlsConfig}.ServeTLS(address)
+}
+
+// ServeTLS starts listening on the given address, and encrypts every
+// connection with TLS, allowing clients to connect with the rtsps scheme.
+func (conf ServerConf) ServeTLS(address string) (*Server, error) {
+	if conf.TLSConfig == nil {
+		return nil, fmt.Errorf("TLSConfig is required")
+	}
+
+	listener, err := tls.Listen("tcp", address, conf.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return conf.serve(listener)
+}
+
+func (conf ServerConf) serve(listener net.Listener) (*Server, error) {
+	rtpListener, err := newServerUDPListener(conf.UDPRTPAddress)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	rtcpListener, err := newServerUDPListener(conf.UDPRTCPAddress)
+	if err != nil {
+		listener.Close()
+		if rtpListener != nil {
+			rtpListener.close()
+		}
+		return nil, err
+	}
+
+	if conf.ReceiverReportInterval == 0 {
+		conf.ReceiverReportInterval = 10 * time.Second
+	}
+	if conf.SenderReportInterval == 0 {
+		conf.SenderReportInterval = 10 * time.Second
+	}
+	if conf.StreamDeadAfter == 0 {
+		conf.StreamDeadAfter = 10 * time.Second
+	}
+
+	var multicastIPRange *net.IPNet
+	var multicastNextIP net.IP
+	if conf.MulticastIPRange != "" {
+		var ipNet *net.IPNet
+		multicastNextIP, ipNet, err = net.ParseCIDR(conf.MulticastIPRange)
+		if err != nil {
+			listener.Close()
+			if rtpListener != nil {
+				rtpListener.close()
+			}
+			if rtcpListener != nil {
+				rtcpListener.close()
+			}
+			return nil, err
+		}
+		multicastIPRange = ipNet
+
+		if conf.MulticastTTL == 0 {
+			conf.MulticastTTL = 16
+		}
+	}
+
+	s := &Server{
+		conf:             conf,
+		listener:         listener,
+		udpRTPListener:   rtpListener,
+		udpRTCPListener:  rtcpListener,
+		multicastIPRange: multicastIPRange,
+		multicastNextIP:  multicastNextIP,
+		multicastGroups:  make(map[string]*serverMulticastListener),
+	}
+
+	if rtpListener != nil {
+		go rtpListener.run(StreamTypeRTP)
+	}
+	if rtcpListener != nil {
+		go rtcpListener.run(StreamTypeRTCP)
+	}
+
+	return s, nil
+}
+
+// Accept accepts a connection.
+func (s *Server) Accept() (*ServerConn, error) {
+	nconn, err := s.listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newServerConn(s, nconn), nil
+}
+
+// Close closes the server.
+func (s *Server) Close() error {
+	if s.udpRTPListener != nil {
+		s.udpRTPListener.close()
+	}
+	if s.udpRTCPListener != nil {
+		s.udpRTCPListener.close()
+	}
+
+	s.multicastMutex.Lock()
+	for key, m := range s.multicastGroups {
+		delete(s.multicastGroups, key)
+		m.close()
+	}
+	s.multicastMutex.Unlock()
+
+	return s.listener.Close()
+}
@@ -0,0 +1,641 @@
+package gortsplib
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aler9/gortsplib/pkg/base"
+	"github.com/aler9/gortsplib/pkg/headers"
+	"github.com/aler9/gortsplib/pkg/rtcpreceiver"
+	"github.com/aler9/gortsplib/pkg/rtcpsender"
+)
+
+// interleavedFrameMagicByte is the first byte of every TCP-interleaved
+// RTP/RTCP frame, as defined in RFC2326, section 10.12.
+const interleavedFrameMagicByte = 0x24
+
+// ServerConnHandlers are the handlers passed to ServerConn.Read().
+type ServerConnHandlers struct {
+	// OnDescribe is called when a DESCRIBE request arrives. It must return
+	// either a SDP body or, to redirect the client elsewhere, a non-empty
+	// redirect URL; the response's status code and Location header are
+	// filled in automatically in the latter case.
+	OnDescribe func(req *base.Request, path string) (res *base.Response, sdp []byte, redirect string, err error)
+
+	// OnAnnounce is called when an ANNOUNCE request arrives.
+	OnAnnounce func(req *base.Request, path string, tracks Tracks, sdp []byte) (*base.Response, error)
+
+	// OnSetup is called when a SETUP request arrives.
+	OnSetup func(req *base.Request, th *headers.Transport, path string, trackID int) (*base.Response, error)
+
+	// OnPlay is called when a PLAY request arrives.
+	OnPlay func(req *base.Request) (*base.Response, error)
+
+	// OnRecord is called when a RECORD request arrives.
+	OnRecord func(req *base.Request) (*base.Response, error)
+
+	// OnPause is called when a PAUSE request arrives.
+	OnPause func(req *base.Request) (*base.Response, error)
+
+	// OnFrame is called when a RTP/RTCP frame arrives. For published tracks,
+	// it is called for every inbound frame sent by the publisher.
+	OnFrame func(trackID int, typ StreamType, buf []byte)
+}
+
+// ServerConn is a server-side RTSP connection.
+type ServerConn struct {
+	server *Server
+	nconn  net.Conn
+	bconn  *bufio.ReadWriter
+
+	writeMutex sync.Mutex
+
+	// mutex guards state, path, tracks and channels, which are written from
+	// the goroutine running backgroundRead and read from runReports and
+	// from any goroutine calling State() or WriteFrame().
+	mutex    sync.Mutex
+	state    ServerConnState
+	path     string
+	tracks   map[int]*streamTrack
+	channels map[int]trackChannel
+
+	handlers ServerConnHandlers
+
+	closeOnce sync.Once
+	rtcpDone  chan struct{}
+
+	rtcpMutex         sync.Mutex
+	rtcpReceivers     map[int]*rtcpreceiver.RTCPReceiver
+	rtcpSenders       map[int]*rtcpsender.RTCPSender
+	lastIncomingFrame time.Time
+}
+
+type streamTrack struct {
+	proto             StreamProtocol
+	interleavedIds    *[2]int
+	clientPorts       *[2]int
+	multicastListener *serverMulticastListener
+	multicastKey      string
+}
+
+// trackChannel identifies the track and stream type (RTP or RTCP) that an
+// interleaved channel number, negotiated in a SETUP request/response, was
+// assigned to.
+type trackChannel struct {
+	trackID int
+	typ     StreamType
+}
+
+func newServerConn(server *Server, nconn net.Conn) *ServerConn {
+	return &ServerConn{
+		server:        server,
+		nconn:         nconn,
+		bconn:         bufio.NewReadWriter(bufio.NewReader(nconn), bufio.NewWriter(nconn)),
+		tracks:        make(map[int]*streamTrack),
+		channels:      make(map[int]trackChannel),
+		rtcpDone:      make(chan struct{}),
+		rtcpReceivers: make(map[int]*rtcpreceiver.RTCPReceiver),
+		rtcpSenders:   make(map[int]*rtcpsender.RTCPSender),
+	}
+}
+
+// Close closes the connection.
+func (sc *ServerConn) Close() error {
+	if sc.server.udpRTPListener != nil {
+		sc.server.udpRTPListener.removeRoutesForConn(sc)
+	}
+	if sc.server.udpRTCPListener != nil {
+		sc.server.udpRTCPListener.removeRoutesForConn(sc)
+	}
+	sc.mutex.Lock()
+	tracks := make([]*streamTrack, 0, len(sc.tracks))
+	for _, track := range sc.tracks {
+		tracks = append(tracks, track)
+	}
+	sc.mutex.Unlock()
+
+	for _, track := range tracks {
+		if track.multicastListener != nil {
+			sc.server.releaseMulticastListener(track.multicastKey)
+		}
+	}
+	sc.closeOnce.Do(func() {
+		close(sc.rtcpDone)
+	})
+	return sc.nconn.Close()
+}
+
+// NetConn returns the underlying net.Conn.
+func (sc *ServerConn) NetConn() net.Conn {
+	return sc.nconn
+}
+
+// State returns the state of the connection.
+func (sc *ServerConn) State() ServerConnState {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	return sc.state
+}
+
+// Read starts reading requests and handling them with the given handlers.
+// It returns a channel that is written to, and closed, when the connection
+// terminates.
+func (sc *ServerConn) Read(handlers ServerConnHandlers) chan error {
+	sc.handlers = handlers
+
+	done := make(chan error, 1)
+
+	go sc.runReports()
+
+	go func() {
+		done <- sc.backgroundRead(handlers)
+		close(done)
+	}()
+
+	return done
+}
+
+func (sc *ServerConn) backgroundRead(handlers ServerConnHandlers) error {
+	frameBuf := make([]byte, 2048)
+
+	for {
+		if err := sc.setReadDeadline(); err != nil {
+			return err
+		}
+
+		byt, err := sc.bconn.Reader.Peek(1)
+		if err != nil {
+			return err
+		}
+
+		if byt[0] == interleavedFrameMagicByte {
+			var fr base.InterleavedFrame
+			fr.Payload = frameBuf
+			if err := fr.Read(sc.bconn.Reader); err != nil {
+				return err
+			}
+
+			sc.mutex.Lock()
+			tc, ok := sc.channels[fr.Channel]
+			sc.mutex.Unlock()
+			if ok {
+				sc.onIncomingFrame(tc.trackID, tc.typ, fr.Payload)
+			}
+			continue
+		}
+
+		var req base.Request
+		if err := req.Read(sc.bconn.Reader); err != nil {
+			return err
+		}
+
+		// writeMutex is held while the request is handled and its response
+		// is written, so that TCP frames written by WriteFrame meanwhile
+		// (e.g. from a goroutine started by OnPlay) follow the response.
+		sc.writeMutex.Lock()
+		res, err := sc.handleRequest(handlers, &req)
+		if err == nil {
+			err = sc.setWriteDeadline()
+		}
+		if err == nil {
+			err = res.Write(sc.bconn.Writer)
+		}
+		sc.writeMutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// setReadDeadline resets the read deadline on the underlying connection,
+// if ServerConf.ReadTimeout is set.
+func (sc *ServerConn) setReadDeadline() error {
+	if sc.server.conf.ReadTimeout > 0 {
+		return sc.nconn.SetReadDeadline(time.Now().Add(sc.server.conf.ReadTimeout))
+	}
+	return nil
+}
+
+// setWriteDeadline resets the write deadline on the underlying connection,
+// if ServerConf.WriteTimeout is set.
+func (sc *ServerConn) setWriteDeadline() error {
+	if sc.server.conf.WriteTimeout > 0 {
+		return sc.nconn.SetWriteDeadline(time.Now().Add(sc.server.conf.WriteTimeout))
+	}
+	return nil
+}
+
+func (sc *ServerConn) handleRequest(handlers ServerConnHandlers, req *base.Request) (*base.Response, error) {
+	switch req.Method {
+	case base.Describe:
+		return sc.handleDescribe(handlers, req)
+
+	case base.Announce:
+		return sc.handleAnnounce(handlers, req)
+
+	case base.Setup:
+		return sc.handleSetup(handlers, req)
+
+	case base.Play:
+		return sc.handlePlay(handlers, req)
+
+	case base.Record:
+		return sc.handleRecord(handlers, req)
+
+	case base.Pause:
+		return sc.handlePause(handlers, req)
+
+	default:
+		return &base.Response{
+			StatusCode: base.StatusMethodNotAllowed,
+		}, nil
+	}
+}
+
+// internalServerError is returned to the client whenever a user handler
+// fails, instead of whatever (possibly nil) response it returned alongside
+// the error.
+func internalServerError() *base.Response {
+	return &base.Response{
+		StatusCode: base.StatusInternalServerError,
+	}
+}
+
+func (sc *ServerConn) handleDescribe(handlers ServerConnHandlers, req *base.Request) (*base.Response, error) {
+	path, _ := setupPathAndTrackID(req.URL)
+
+	if handlers.OnDescribe == nil {
+		return &base.Response{
+			StatusCode: base.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	res, sdp, redirect, err := handlers.OnDescribe(req, path)
+	if err != nil {
+		return internalServerError(), nil
+	}
+
+	if redirect != "" {
+		if res.Header == nil {
+			res.Header = make(base.Header)
+		}
+		res.StatusCode = base.StatusFound
+		res.Header["Location"] = base.HeaderValue{redirect}
+		return res, nil
+	}
+
+	if res.StatusCode == base.StatusOK {
+		if res.Header == nil {
+			res.Header = make(base.Header)
+		}
+		res.Header["Content-Type"] = base.HeaderValue{"application/sdp"}
+		res.Body = sdp
+	}
+
+	return res, nil
+}
+
+func (sc *ServerConn) handleAnnounce(handlers ServerConnHandlers, req *base.Request) (*base.Response, error) {
+	if sc.State() != ServerConnStateInitial {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	ct, ok := req.Header["Content-Type"]
+	if !ok || len(ct) != 1 || ct[0] != "application/sdp" {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	tracks, err := ReadTracks(req.Body)
+	if err != nil {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	path, _ := setupPathAndTrackID(req.URL)
+
+	if handlers.OnAnnounce == nil {
+		return &base.Response{
+			StatusCode: base.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	res, err := handlers.OnAnnounce(req, path, tracks, req.Body)
+	if err != nil {
+		return internalServerError(), nil
+	}
+
+	if res.StatusCode == base.StatusOK {
+		sc.mutex.Lock()
+		sc.path = path
+		sc.state = ServerConnStatePreRecord
+		sc.mutex.Unlock()
+	}
+
+	return res, nil
+}
+
+func (sc *ServerConn) handleSetup(handlers ServerConnHandlers, req *base.Request) (*base.Response, error) {
+	path, trackID := setupPathAndTrackID(req.URL)
+
+	sc.mutex.Lock()
+	existingPath := sc.path
+	sc.mutex.Unlock()
+
+	if existingPath != "" && existingPath != path {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	th, err := headers.ReadTransport(req.Header["Transport"])
+	if err != nil {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	if handlers.OnSetup == nil {
+		return &base.Response{
+			StatusCode: base.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	res, err := handlers.OnSetup(req, th, path, trackID)
+	if err != nil {
+		return internalServerError(), nil
+	}
+
+	if res.StatusCode == base.StatusOK {
+		isMulticast := th.Delivery != nil && *th.Delivery == base.StreamDeliveryMulticast
+
+		if isMulticast && th.Mode != nil && *th.Mode == headers.TransportModeRecord {
+			return &base.Response{
+				StatusCode: base.StatusUnsupportedTransport,
+			}, nil
+		}
+
+		if isMulticast && sc.server.conf.MulticastIPRange == "" {
+			return &base.Response{
+				StatusCode: base.StatusUnsupportedTransport,
+			}, nil
+		}
+
+		track := &streamTrack{
+			proto:          th.Protocol,
+			interleavedIds: th.InterleavedIds,
+			clientPorts:    th.ClientPorts,
+		}
+
+		switch {
+		case th.Protocol == StreamProtocolUDP && isMulticast:
+			m, key, err := sc.server.multicastListenerForTrack(path, trackID)
+			if err != nil {
+				return &base.Response{
+					StatusCode: base.StatusInternalServerError,
+				}, nil
+			}
+
+			track.multicastListener = m
+			track.multicastKey = key
+
+			dest := m.ip.String()
+			ttl := sc.server.conf.MulticastTTL
+			th.Destination = &dest
+			th.TTL = &ttl
+			th.ServerPorts = &[2]int{m.rtpPort, m.rtcpPort}
+
+		case th.Protocol == StreamProtocolUDP && sc.server.udpRTPListener != nil && sc.server.udpRTCPListener != nil:
+			th.ServerPorts = &[2]int{sc.server.udpRTPListener.port, sc.server.udpRTCPListener.port}
+
+			if host, _, err := net.SplitHostPort(sc.nconn.RemoteAddr().String()); err == nil && th.ClientPorts != nil {
+				sc.server.udpRTPListener.addRoute(
+					fmt.Sprintf("%s:%d", host, th.ClientPorts[0]), sc, trackID)
+				sc.server.udpRTCPListener.addRoute(
+					fmt.Sprintf("%s:%d", host, th.ClientPorts[1]), sc, trackID)
+			}
+		}
+
+		sc.mutex.Lock()
+		sc.path = path
+		sc.tracks[trackID] = track
+		if track.interleavedIds != nil {
+			sc.channels[track.interleavedIds[0]] = trackChannel{trackID, StreamTypeRTP}
+			sc.channels[track.interleavedIds[1]] = trackChannel{trackID, StreamTypeRTCP}
+		}
+		if sc.state == ServerConnStateInitial {
+			sc.state = ServerConnStatePrePlay
+		}
+		sc.mutex.Unlock()
+
+		if res.Header == nil {
+			res.Header = make(base.Header)
+		}
+		res.Header["Transport"] = th.Write()
+	}
+
+	return res, nil
+}
+
+func (sc *ServerConn) handlePlay(handlers ServerConnHandlers, req *base.Request) (*base.Response, error) {
+	state := sc.State()
+	if state != ServerConnStatePrePlay && state != ServerConnStatePlay {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	if handlers.OnPlay == nil {
+		return &base.Response{
+			StatusCode: base.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	res, err := handlers.OnPlay(req)
+	if err != nil {
+		return internalServerError(), nil
+	}
+
+	if res.StatusCode == base.StatusOK {
+		sc.mutex.Lock()
+		sc.state = ServerConnStatePlay
+		sc.mutex.Unlock()
+	}
+
+	return res, nil
+}
+
+func (sc *ServerConn) handleRecord(handlers ServerConnHandlers, req *base.Request) (*base.Response, error) {
+	if sc.State() != ServerConnStatePreRecord {
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	if handlers.OnRecord == nil {
+		return &base.Response{
+			StatusCode: base.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	res, err := handlers.OnRecord(req)
+	if err != nil {
+		return internalServerError(), nil
+	}
+
+	if res.StatusCode == base.StatusOK {
+		sc.mutex.Lock()
+		sc.state = ServerConnStateRecord
+		sc.mutex.Unlock()
+
+		// StreamDeadAfter is counted from the start of RECORD until the
+		// first frame arrives.
+		sc.rtcpMutex.Lock()
+		sc.lastIncomingFrame = time.Now()
+		sc.rtcpMutex.Unlock()
+	}
+
+	return res, nil
+}
+
+func (sc *ServerConn) handlePause(handlers ServerConnHandlers, req *base.Request) (*base.Response, error) {
+	switch sc.State() {
+	case ServerConnStatePrePlay, ServerConnStatePlay, ServerConnStateRecord, ServerConnStatePreRecord:
+
+	default:
+		return &base.Response{
+			StatusCode: base.StatusBadRequest,
+		}, nil
+	}
+
+	if handlers.OnPause == nil {
+		return &base.Response{
+			StatusCode: base.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	res, err := handlers.OnPause(req)
+	if err != nil {
+		return internalServerError(), nil
+	}
+
+	if res.StatusCode == base.StatusOK {
+		sc.mutex.Lock()
+		if sc.state == ServerConnStateRecord {
+			sc.state = ServerConnStatePreRecord
+		} else {
+			sc.state = ServerConnStatePrePlay
+		}
+		sc.mutex.Unlock()
+	}
+
+	return res, nil
+}
+
+// WriteFrame writes a RTP/RTCP frame to the connection. It fails if trackID
+// was setup in multicast mode: use Server.WriteFrameMulticast instead, which
+// writes the shared multicast group once regardless of how many readers
+// have setup that path/track.
+func (sc *ServerConn) WriteFrame(trackID int, typ StreamType, payload []byte) error {
+	sc.mutex.Lock()
+	track, ok := sc.tracks[trackID]
+	sc.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("track %d has not been setup", trackID)
+	}
+
+	sc.onOutgoingFrame(trackID, typ, payload)
+
+	if track.proto == StreamProtocolTCP {
+		channel := track.interleavedIds[0]
+		if typ == StreamTypeRTCP {
+			channel = track.interleavedIds[1]
+		}
+
+		sc.writeMutex.Lock()
+		defer sc.writeMutex.Unlock()
+
+		if err := sc.setWriteDeadline(); err != nil {
+			return err
+		}
+
+		return base.InterleavedFrame{
+			Channel: channel,
+			Payload: payload,
+		}.Write(sc.bconn.Writer)
+	}
+
+	if track.multicastListener != nil {
+		// the multicast listener is shared by every reader of this
+		// path/track: writing it here, once per ServerConn, would send a
+		// duplicate UDP packet per reader. Server.WriteFrameMulticast is
+		// the single entry point meant to feed it, typically called once
+		// per frame from the publisher's OnFrame handler.
+		return fmt.Errorf("track %d is in multicast mode: call Server.WriteFrameMulticast instead of WriteFrame", trackID)
+	}
+
+	listener := sc.server.udpRTPListener
+	clientPort := track.clientPorts[0]
+	if typ == StreamTypeRTCP {
+		listener = sc.server.udpRTCPListener
+		clientPort = track.clientPorts[1]
+	}
+	if listener == nil {
+		return fmt.Errorf("UDP is not configured on this server")
+	}
+
+	host, _, err := net.SplitHostPort(sc.nconn.RemoteAddr().String())
+	if err != nil {
+		return err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", host, clientPort))
+	if err != nil {
+		return err
+	}
+
+	_, err = listener.pc.WriteTo(payload, addr)
+	return err
+}
+
+// setupPathAndTrackID extracts the path and the track ID from a SETUP URL.
+//
+// the track ID is normally appended as the last path component
+// (e.g. "/teststream/trackID=2"), but some clients (e.g. ffmpeg when
+// publishing mpegts) omit it, and others (e.g. VLC) place it after the query
+// string (e.g. "/teststream?param=1/trackID=2").
+func setupPathAndTrackID(u *base.URL) (string, int) {
+	raw := strings.TrimPrefix(u.Path, "/")
+	if u.RawQuery != "" {
+		raw += "?" + u.RawQuery
+	}
+
+	trackID := 0
+
+	if i := strings.LastIndexByte(raw, '/'); i >= 0 {
+		last := raw[i+1:]
+		if strings.HasPrefix(last, "trackID=") {
+			if n, err := strconv.Atoi(last[len("trackID="):]); err == nil {
+				trackID = n
+			}
+			raw = raw[:i]
+		} else if last == "" {
+			raw = raw[:i]
+		}
+	}
+
+	if i := strings.IndexByte(raw, '?'); i >= 0 {
+		raw = raw[:i]
+	}
+
+	return raw, trackID
+}
@@ -2,17 +2,52 @@ package gortsplib
 
 import (
 	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"io"
+	"math/big"
 	"net"
 	"testing"
 	"time"
 
+	psdp "github.com/pion/sdp/v2"
 	"github.com/stretchr/testify/require"
 
 	"github.com/aler9/gortsplib/pkg/base"
 	"github.com/aler9/gortsplib/pkg/headers"
 )
 
+// generateServerTLSConfig returns a TLSConfig carrying a throwaway
+// self-signed certificate, for use with ServerConf.ServeTLS in tests.
+func generateServerTLSConfig(t *testing.T) *tls.Config {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
+
 func TestServerConnReadSetupPath(t *testing.T) {
 	for _, ca := range []struct {
 		name    string
@@ -85,7 +120,7 @@ func TestServerConnReadSetupPath(t *testing.T) {
 					}, nil
 				}
 
-				err = <-conn.Read(ServerConnReadHandlers{
+				err = <-conn.Read(ServerConnHandlers{
 					OnSetup: onSetup,
 				})
 				require.Equal(t, io.EOF, err)
@@ -151,7 +186,7 @@ func TestServerConnReadSetupDifferentPaths(t *testing.T) {
 			}, nil
 		}
 
-		<-conn.Read(ServerConnReadHandlers{
+		<-conn.Read(ServerConnHandlers{
 			OnSetup: onSetup,
 		})
 	}()
@@ -251,7 +286,7 @@ func TestServerConnReadReceivePackets(t *testing.T) {
 					close(packetsReceived)
 				}
 
-				err = <-conn.Read(ServerConnReadHandlers{
+				err = <-conn.Read(ServerConnHandlers{
 					OnSetup: onSetup,
 					OnPlay:  onPlay,
 					OnFrame: onFrame,
@@ -327,9 +362,409 @@ func TestServerConnReadReceivePackets(t *testing.T) {
 				})
 			} else {
 				err = base.InterleavedFrame{
-					TrackID:    0,
-					StreamType: StreamTypeRTCP,
-					Payload:    []byte("\x01\x02\x03\x04"),
+					Channel: 1,
+					Payload: []byte("\x01\x02\x03\x04"),
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+			}
+
+			<-packetsReceived
+		})
+	}
+}
+
+func TestServerConnReadSetupMulticast(t *testing.T) {
+	conf := ServerConf{
+		MulticastIPRange: "224.1.0.0/16",
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		onSetup := func(req *base.Request, th *headers.Transport, path string, trackID int) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		for i := 0; i < 2; i++ {
+			conn, err := s.Accept()
+			require.NoError(t, err)
+
+			go func() {
+				defer conn.Close()
+				<-conn.Read(ServerConnHandlers{
+					OnSetup: onSetup,
+				})
+			}()
+		}
+	}()
+
+	var destinations [2]string
+	var serverPorts [2]*[2]int
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		th := &headers.Transport{
+			Protocol: StreamProtocolUDP,
+			Delivery: func() *base.StreamDelivery {
+				v := base.StreamDeliveryMulticast
+				return &v
+			}(),
+			Mode: func() *headers.TransportMode {
+				v := headers.TransportModePlay
+				return &v
+			}(),
+		}
+
+		err = base.Request{
+			Method: base.Setup,
+			URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+			Header: base.Header{
+				"CSeq":      base.HeaderValue{"1"},
+				"Transport": th.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		var res base.Response
+		err = res.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		rth, err := headers.ReadTransport(res.Header["Transport"])
+		require.NoError(t, err)
+		require.NotNil(t, rth.Destination)
+		require.NotNil(t, rth.ServerPorts)
+
+		destinations[i] = *rth.Destination
+		serverPorts[i] = rth.ServerPorts
+	}
+
+	// both readers are handed the same multicast group.
+	require.Equal(t, destinations[0], destinations[1])
+	require.Equal(t, serverPorts[0], serverPorts[1])
+
+	packetsReceived := make(chan struct{}, 2)
+
+	for i := 0; i < 2; i++ {
+		l, err := net.ListenMulticastUDP("udp", nil, &net.UDPAddr{
+			IP:   net.ParseIP(destinations[0]),
+			Port: serverPorts[0][0],
+		})
+		require.NoError(t, err)
+		defer l.Close()
+
+		go func() {
+			buf := make([]byte, 2048)
+			n, _, err := l.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			require.Equal(t, []byte("\x01\x02\x03\x04"), buf[:n])
+			packetsReceived <- struct{}{}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	// a single call reaches both readers with no duplication.
+	err = s.WriteFrameMulticast("teststream", 0, StreamTypeRTP, []byte("\x01\x02\x03\x04"))
+	require.NoError(t, err)
+
+	<-packetsReceived
+	<-packetsReceived
+}
+
+func TestServerConnRecordStreamDeadAfter(t *testing.T) {
+	conf := ServerConf{
+		StreamDeadAfter: 200 * time.Millisecond,
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverErr := make(chan error)
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onAnnounce := func(req *base.Request, path string, tracks Tracks, sdp []byte) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onSetup := func(req *base.Request, th *headers.Transport, path string, trackID int) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onRecord := func(req *base.Request) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		serverErr <- <-conn.Read(ServerConnHandlers{
+			OnAnnounce: onAnnounce,
+			OnSetup:    onSetup,
+			OnRecord:   onRecord,
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	tracks := Tracks{{
+		ID: 0,
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "application",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{"96"},
+			},
+		},
+	}}
+
+	err = base.Request{
+		Method: base.Announce,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":         base.HeaderValue{"1"},
+			"Content-Type": base.HeaderValue{"application/sdp"},
+		},
+		Body: WriteTracks(tracks),
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"2"},
+			"Transport": headers.Transport{
+				Protocol: StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModeRecord
+					return &v
+				}(),
+				InterleavedIds: &[2]int{0, 1},
+			}.Write(),
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	err = base.Request{
+		Method: base.Record,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"3"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// the publisher never sends a frame: the session must be closed once
+	// StreamDeadAfter elapses.
+	select {
+	case err := <-serverErr:
+		require.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection was not closed after StreamDeadAfter")
+	}
+}
+
+func TestServerConnRecordReceivePackets(t *testing.T) {
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			packetsReceived := make(chan struct{})
+
+			conf := ServerConf{
+				UDPRTPAddress:  "127.0.0.1:8000",
+				UDPRTCPAddress: "127.0.0.1:8001",
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				onAnnounce := func(req *base.Request, path string, tracks Tracks, sdp []byte) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onSetup := func(req *base.Request, th *headers.Transport, path string, trackID int) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onRecord := func(req *base.Request) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onFrame := func(trackID int, typ StreamType, buf []byte) {
+					require.Equal(t, 0, trackID)
+					require.Equal(t, StreamTypeRTP, typ)
+					close(packetsReceived)
+				}
+
+				err = <-conn.Read(ServerConnHandlers{
+					OnAnnounce: onAnnounce,
+					OnSetup:    onSetup,
+					OnRecord:   onRecord,
+					OnFrame:    onFrame,
+				})
+				require.Error(t, err)
+			}()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+			tracks := Tracks{{
+				ID: 0,
+				Media: &psdp.MediaDescription{
+					MediaName: psdp.MediaName{
+						Media:   "application",
+						Protos:  []string{"RTP", "AVP"},
+						Formats: []string{"96"},
+					},
+				},
+			}}
+
+			err = base.Request{
+				Method: base.Announce,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":         base.HeaderValue{"1"},
+					"Content-Type": base.HeaderValue{"application/sdp"},
+				},
+				Body: WriteTracks(tracks),
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			var res base.Response
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			th := &headers.Transport{
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModeRecord
+					return &v
+				}(),
+			}
+
+			if proto == "udp" {
+				th.Protocol = StreamProtocolUDP
+				th.ClientPorts = &[2]int{35468, 35469}
+			} else {
+				th.Protocol = StreamProtocolTCP
+				th.InterleavedIds = &[2]int{0, 1}
+			}
+
+			err = base.Request{
+				Method: base.Setup,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq":      base.HeaderValue{"2"},
+					"Transport": th.Write(),
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			th, err = headers.ReadTransport(res.Header["Transport"])
+			require.NoError(t, err)
+
+			err = base.Request{
+				Method: base.Record,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"3"},
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			rtpPacket := []byte("\x80\x60\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+
+			if proto == "udp" {
+				l1, err := net.ListenPacket("udp", "localhost:35468")
+				require.NoError(t, err)
+				defer l1.Close()
+
+				l1.WriteTo(rtpPacket, &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ServerPorts[0],
+				})
+			} else {
+				err = base.InterleavedFrame{
+					Channel: 0,
+					Payload: rtpPacket,
 				}.Write(bconn.Writer)
 				require.NoError(t, err)
 			}
@@ -339,6 +774,104 @@ func TestServerConnReadReceivePackets(t *testing.T) {
 	}
 }
 
+func TestServerConnReadTLS(t *testing.T) {
+	conf := ServerConf{
+		TLSConfig: generateServerTLSConfig(t),
+	}
+
+	s, err := conf.ServeTLS("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onSetup := func(req *base.Request, th *headers.Transport, path string, trackID int) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		err = <-conn.Read(ServerConnHandlers{
+			OnSetup: onSetup,
+		})
+		require.Equal(t, io.EOF, err)
+	}()
+
+	conn, err := tls.Dial("tcp", "localhost:8554", &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIds: &[2]int{0, 1},
+			}.Write(),
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+}
+
+func TestServerConnReadTimeout(t *testing.T) {
+	conf := ServerConf{
+		ReadTimeout: 200 * time.Millisecond,
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverErr := make(chan error)
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		serverErr <- <-conn.Read(ServerConnHandlers{})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the client never sends a request: the connection must be closed once
+	// ReadTimeout elapses.
+	select {
+	case err := <-serverErr:
+		require.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection was not closed after ReadTimeout")
+	}
+}
+
 func TestServerConnReadTCPResponseBeforeFrames(t *testing.T) {
 	s, err := Serve("127.0.0.1:8554")
 	require.NoError(t, err)
@@ -390,7 +923,7 @@ func TestServerConnReadTCPResponseBeforeFrames(t *testing.T) {
 			}, nil
 		}
 
-		err = <-conn.Read(ServerConnReadHandlers{
+		err = <-conn.Read(ServerConnHandlers{
 			OnSetup: onSetup,
 			OnPlay:  onPlay,
 		})
@@ -496,7 +1029,7 @@ func TestServerConnReadPlayMultiple(t *testing.T) {
 			}, nil
 		}
 
-		err = <-conn.Read(ServerConnReadHandlers{
+		err = <-conn.Read(ServerConnHandlers{
 			OnSetup: onSetup,
 			OnPlay:  onPlay,
 		})
@@ -615,7 +1148,7 @@ func TestServerConnReadPauseMultiple(t *testing.T) {
 			}, nil
 		}
 
-		err = <-conn.Read(ServerConnReadHandlers{
+		err = <-conn.Read(ServerConnHandlers{
 			OnSetup: onSetup,
 			OnPlay:  onPlay,
 			OnPause: onPause,
@@ -694,4 +1227,100 @@ func TestServerConnReadPauseMultiple(t *testing.T) {
 	err = res.ReadIgnoreFrames(bconn.Reader, buf)
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
-}
\ No newline at end of file
+}
+
+func TestServerConnReadDescribe(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onDescribe := func(req *base.Request, path string) (*base.Response, []byte, string, error) {
+			require.Equal(t, "teststream", path)
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, []byte("fakesdp"), "", nil
+		}
+
+		err = <-conn.Read(ServerConnHandlers{
+			OnDescribe: onDescribe,
+		})
+		require.Equal(t, io.EOF, err)
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Describe,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.HeaderValue{"application/sdp"}, res.Header["Content-Type"])
+	require.Equal(t, []byte("fakesdp"), res.Body)
+}
+
+func TestServerConnReadDescribeRedirect(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onDescribe := func(req *base.Request, path string) (*base.Response, []byte, string, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil, "rtsp://localhost:8554/otherstream", nil
+		}
+
+		err = <-conn.Read(ServerConnHandlers{
+			OnDescribe: onDescribe,
+		})
+		require.Equal(t, io.EOF, err)
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Describe,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusFound, res.StatusCode)
+	require.Equal(t, base.HeaderValue{"rtsp://localhost:8554/otherstream"}, res.Header["Location"])
+}
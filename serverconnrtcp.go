@@ -0,0 +1,147 @@
+package gortsplib
+
+import (
+	"time"
+
+	"github.com/aler9/gortsplib/pkg/rtcpreceiver"
+	"github.com/aler9/gortsplib/pkg/rtcpsender"
+)
+
+// onIncomingFrame is called for every frame read from the connection,
+// whether TCP-interleaved or UDP, before it reaches the user's OnFrame
+// handler. It feeds the per-track RTCP receiver used to build automatic
+// receiver reports during RECORD.
+func (sc *ServerConn) onIncomingFrame(trackID int, typ StreamType, payload []byte) {
+	now := time.Now()
+
+	sc.rtcpMutex.Lock()
+	sc.lastIncomingFrame = now
+	sc.rtcpMutex.Unlock()
+
+	if typ == StreamTypeRTP {
+		sc.rtcpMutex.Lock()
+		rr, ok := sc.rtcpReceivers[trackID]
+		if !ok {
+			rr = rtcpreceiver.New(uint32(trackID) + 1)
+			sc.rtcpReceivers[trackID] = rr
+		}
+		sc.rtcpMutex.Unlock()
+
+		rr.ProcessPacket(payload, now)
+	}
+
+	if sc.handlers.OnFrame != nil {
+		sc.handlers.OnFrame(trackID, typ, payload)
+	}
+}
+
+// onOutgoingFrame is called for every RTP frame written with WriteFrame. It
+// feeds the per-track RTCP sender used to build automatic sender reports
+// during PLAY.
+func (sc *ServerConn) onOutgoingFrame(trackID int, typ StreamType, payload []byte) {
+	if typ != StreamTypeRTP {
+		return
+	}
+
+	sc.rtcpMutex.Lock()
+	rs, ok := sc.rtcpSenders[trackID]
+	if !ok {
+		rs = rtcpsender.New()
+		sc.rtcpSenders[trackID] = rs
+	}
+	sc.rtcpMutex.Unlock()
+
+	rs.ProcessPacket(payload, time.Now())
+}
+
+// lastIncomingFrameTime returns the time the most recent RTP/RTCP frame was
+// received on any track, used to detect a dead publisher.
+func (sc *ServerConn) lastIncomingFrameTime() time.Time {
+	sc.rtcpMutex.Lock()
+	defer sc.rtcpMutex.Unlock()
+	return sc.lastIncomingFrame
+}
+
+// sendReports emits a RTCP sender or receiver report for every track of the
+// active session.
+func (sc *ServerConn) sendReports(now time.Time) {
+	switch sc.State() {
+	case ServerConnStatePlay:
+		sc.rtcpMutex.Lock()
+		senders := make(map[int]*rtcpsender.RTCPSender, len(sc.rtcpSenders))
+		for trackID, rs := range sc.rtcpSenders {
+			senders[trackID] = rs
+		}
+		sc.rtcpMutex.Unlock()
+
+		for trackID, rs := range senders {
+			sr := rs.Report(now)
+			if sr == nil {
+				continue
+			}
+
+			byts, err := sr.Marshal()
+			if err != nil {
+				continue
+			}
+
+			sc.WriteFrame(trackID, StreamTypeRTCP, byts)
+		}
+
+	case ServerConnStateRecord:
+		sc.rtcpMutex.Lock()
+		receivers := make(map[int]*rtcpreceiver.RTCPReceiver, len(sc.rtcpReceivers))
+		for trackID, rr := range sc.rtcpReceivers {
+			receivers[trackID] = rr
+		}
+		sc.rtcpMutex.Unlock()
+
+		for trackID, rr := range receivers {
+			byts, err := rr.Report(now).Marshal()
+			if err != nil {
+				continue
+			}
+
+			sc.WriteFrame(trackID, StreamTypeRTCP, byts)
+		}
+	}
+}
+
+// runReports periodically emits RTCP sender/receiver reports for the
+// connection's active session, and closes it if its publisher has stopped
+// sending RTP/RTCP for longer than ServerConf.StreamDeadAfter.
+func (sc *ServerConn) runReports() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	lastSenderReport := time.Now()
+	lastReceiverReport := time.Now()
+
+	for {
+		select {
+		case <-sc.rtcpDone:
+			return
+
+		case now := <-ticker.C:
+			switch sc.State() {
+			case ServerConnStatePlay:
+				if now.Sub(lastSenderReport) >= sc.server.conf.SenderReportInterval {
+					lastSenderReport = now
+					sc.sendReports(now)
+				}
+
+			case ServerConnStateRecord:
+				if now.Sub(lastReceiverReport) >= sc.server.conf.ReceiverReportInterval {
+					lastReceiverReport = now
+					sc.sendReports(now)
+				}
+
+				if last := sc.lastIncomingFrameTime(); !last.IsZero() &&
+					now.Sub(last) > sc.server.conf.StreamDeadAfter {
+					sc.Close()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package gortsplib
+
+// ServerConnState is the state of a ServerConn.
+type ServerConnState int
+
+// states of a ServerConn.
+const (
+	ServerConnStateInitial ServerConnState = iota
+	ServerConnStatePrePlay
+	ServerConnStatePlay
+	ServerConnStatePreRecord
+	ServerConnStateRecord
+)
+
+// String implements fmt.Stringer.
+func (s ServerConnState) String() string {
+	switch s {
+	case ServerConnStateInitial:
+		return "initial"
+	case ServerConnStatePrePlay:
+		return "prePlay"
+	case ServerConnStatePlay:
+		return "play"
+	case ServerConnStatePreRecord:
+		return "preRecord"
+	case ServerConnStateRecord:
+		return "record"
+	}
+	return "unknown"
+}
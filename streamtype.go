@@ -0,0 +1,23 @@
+package gortsplib
+
+import (
+	"github.com/aler9/gortsplib/pkg/base"
+)
+
+// StreamType is the type of a stream (RTP or RTCP).
+type StreamType = base.StreamType
+
+// stream types.
+const (
+	StreamTypeRTP  = base.StreamTypeRTP
+	StreamTypeRTCP = base.StreamTypeRTCP
+)
+
+// StreamProtocol is the protocol used to stream RTP/RTCP frames (UDP or TCP).
+type StreamProtocol = base.StreamProtocol
+
+// stream protocols.
+const (
+	StreamProtocolUDP = base.StreamProtocolUDP
+	StreamProtocolTCP = base.StreamProtocolTCP
+)
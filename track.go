@@ -0,0 +1,18 @@
+package gortsplib
+
+import (
+	psdp "github.com/pion/sdp/v2"
+)
+
+// Track is a RTSP track.
+type Track struct {
+	// ID is the track identifier, used in the SETUP URL (trackID=N) and in
+	// the SDP control attribute.
+	ID int
+
+	// Media is the SDP media description associated with the track.
+	Media *psdp.MediaDescription
+}
+
+// Tracks is a list of tracks.
+type Tracks []*Track